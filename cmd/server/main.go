@@ -0,0 +1,59 @@
+// Command server runs the Bitespeed identify endpoint as a standalone HTTP
+// service, so the same handler logic that powers the Lambda deployment can
+// be driven locally with `go run`, in a container, or anywhere else outside
+// API Gateway.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/monkwithamac23/bitespeed_backend_/internal/config"
+	"github.com/monkwithamac23/bitespeed_backend_/internal/db"
+	"github.com/monkwithamac23/bitespeed_backend_/internal/handler"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := db.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		if err := db.Migrate(conn); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	h := handler.NewHandler(conn)
+
+	e := echo.New()
+	e.Use(middleware.RequestID())
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+
+	e.GET("/healthz", func(c echo.Context) error {
+		if err := db.Ping(c.Request().Context()); err != nil {
+			return c.String(http.StatusServiceUnavailable, err.Error())
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	h.RegisterRoutes(e)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Fatal(e.Start(":" + port))
+}
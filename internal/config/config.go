@@ -0,0 +1,143 @@
+// Package config loads the database settings ConnectDB needs from the
+// environment (with an optional YAML overlay and AWS secret lookup),
+// instead of the host/port/user/password/dbname constants the source used
+// to carry.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds everything ConnectDB needs to open and tune the database
+// pool.
+type Config struct {
+	DatabaseURL     string
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+const (
+	defaultSSLMode         = "require"
+	defaultMaxOpenConns    = 10
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 5 * time.Minute
+)
+
+// Load assembles Config from, in increasing priority: defaults, an optional
+// YAML file at CONFIG_FILE, an optional AWS secret (see secrets.go), and
+// environment variables. It fails fast with every missing setting named in
+// a single error, rather than letting the first sql.Open die with an
+// opaque connection error.
+func Load() (Config, error) {
+	cfg := Config{
+		SSLMode:         defaultSSLMode,
+		MaxOpenConns:    defaultMaxOpenConns,
+		MaxIdleConns:    defaultMaxIdleConns,
+		ConnMaxLifetime: defaultConnMaxLifetime,
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := overlayYAMLFile(&cfg, path); err != nil {
+			return cfg, fmt.Errorf("config: reading %s: %w", path, err)
+		}
+	}
+
+	if err := overlaySecrets(&cfg); err != nil {
+		return cfg, fmt.Errorf("config: loading secrets: %w", err)
+	}
+
+	overlayEnv(&cfg)
+
+	return cfg, cfg.validate()
+}
+
+func overlayYAMLFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, cfg)
+}
+
+func overlayEnv(cfg *Config) {
+	setString(&cfg.DatabaseURL, "DATABASE_URL")
+	setString(&cfg.Host, "DB_HOST")
+	setInt(&cfg.Port, "DB_PORT")
+	setString(&cfg.User, "DB_USER")
+	setString(&cfg.Password, "DB_PASSWORD")
+	setString(&cfg.DBName, "DB_NAME")
+	setString(&cfg.SSLMode, "DB_SSLMODE")
+	setInt(&cfg.MaxOpenConns, "DB_MAX_OPEN_CONNS")
+	setInt(&cfg.MaxIdleConns, "DB_MAX_IDLE_CONNS")
+	setDuration(&cfg.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME")
+}
+
+func setString(dst *string, key string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func setInt(dst *int, key string) {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			*dst = n
+		}
+	}
+}
+
+func setDuration(dst *time.Duration, key string) {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			*dst = d
+		}
+	}
+}
+
+// validate reports every missing setting at once. DATABASE_URL stands in
+// for Host/User/DBName when set; otherwise all three are required.
+func (c Config) validate() error {
+	if c.DatabaseURL != "" {
+		return nil
+	}
+
+	var missing []string
+	if c.Host == "" {
+		missing = append(missing, "DB_HOST")
+	}
+	if c.User == "" {
+		missing = append(missing, "DB_USER")
+	}
+	if c.DBName == "" {
+		missing = append(missing, "DB_NAME")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("config: missing required settings (or set DATABASE_URL instead): %v", missing)
+	}
+
+	return nil
+}
+
+// ConnectionString returns the libpq connection string ConnectDB should
+// open, preferring DatabaseURL when set.
+func (c Config) ConnectionString() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// overlaySecrets pulls DB settings from AWS Secrets Manager or SSM
+// Parameter Store when running under Lambda and the corresponding env var
+// points at one. It's a no-op everywhere else so local and container runs
+// never need AWS credentials.
+func overlaySecrets(cfg *Config) error {
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") == "" {
+		return nil
+	}
+
+	if arn := os.Getenv("DB_SECRET_ARN"); arn != "" {
+		return overlaySecretsManager(cfg, arn)
+	}
+
+	if path := os.Getenv("DB_SSM_PARAMETER_PATH"); path != "" {
+		return overlaySSM(cfg, path)
+	}
+
+	return nil
+}
+
+// secretManagerPayload mirrors the JSON shape of an AWS RDS-managed
+// Secrets Manager secret, which uses "username" rather than "user" (and a
+// numeric "port"). We decode into this explicitly and map it onto Config
+// by hand, rather than unmarshaling into Config directly and hoping its Go
+// field names happen to match whatever keys the secret actually has.
+type secretManagerPayload struct {
+	DatabaseURL string      `json:"database_url"`
+	Host        string      `json:"host"`
+	Port        json.Number `json:"port"`
+	Username    string      `json:"username"`
+	Password    string      `json:"password"`
+	DBName      string      `json:"dbname"`
+	SSLMode     string      `json:"sslmode"`
+}
+
+func overlaySecretsManager(cfg *Config, arn string) error {
+	ctx := context.Background()
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := secretsmanager.NewFromConfig(awsCfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: &arn,
+	})
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(*out.SecretString))
+	decoder.UseNumber()
+
+	var payload secretManagerPayload
+	if err := decoder.Decode(&payload); err != nil {
+		return err
+	}
+
+	applySecretManagerPayload(cfg, payload)
+
+	return nil
+}
+
+func applySecretManagerPayload(cfg *Config, payload secretManagerPayload) {
+	if payload.DatabaseURL != "" {
+		cfg.DatabaseURL = payload.DatabaseURL
+	}
+	if payload.Host != "" {
+		cfg.Host = payload.Host
+	}
+	if payload.Port != "" {
+		if n, err := payload.Port.Int64(); err == nil {
+			cfg.Port = int(n)
+		}
+	}
+	if payload.Username != "" {
+		cfg.User = payload.Username
+	}
+	if payload.Password != "" {
+		cfg.Password = payload.Password
+	}
+	if payload.DBName != "" {
+		cfg.DBName = payload.DBName
+	}
+	if payload.SSLMode != "" {
+		cfg.SSLMode = payload.SSLMode
+	}
+}
+
+func overlaySSM(cfg *Config, path string) error {
+	ctx := context.Background()
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	withDecryption := true
+	out, err := ssm.NewFromConfig(awsCfg).GetParametersByPath(ctx, &ssm.GetParametersByPathInput{
+		Path:           &path,
+		WithDecryption: &withDecryption,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range out.Parameters {
+		applySSMParameter(cfg, lastSegment(*p.Name), *p.Value)
+	}
+
+	return nil
+}
+
+func applySSMParameter(cfg *Config, name, value string) {
+	switch name {
+	case "database_url":
+		cfg.DatabaseURL = value
+	case "host":
+		cfg.Host = value
+	case "port":
+		if n, err := strconv.Atoi(value); err == nil {
+			cfg.Port = n
+		}
+	case "user":
+		cfg.User = value
+	case "password":
+		cfg.Password = value
+	case "dbname":
+		cfg.DBName = value
+	case "sslmode":
+		cfg.SSLMode = value
+	}
+}
+
+func lastSegment(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
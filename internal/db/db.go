@@ -0,0 +1,48 @@
+// Package db owns the process-wide database pool and the embedded schema
+// migrations it depends on.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	_ "github.com/lib/pq"
+
+	"github.com/monkwithamac23/bitespeed_backend_/internal/config"
+)
+
+var (
+	once     sync.Once
+	instance *sql.DB
+	openErr  error
+)
+
+// Open returns the process-wide database pool, opening it on first call
+// and reusing it on every later call. A Lambda cold start pays the dial
+// cost once; warm invocations, and the HTTP server, reuse the same pool
+// rather than opening a fresh one per request.
+func Open(cfg config.Config) (*sql.DB, error) {
+	once.Do(func() {
+		instance, openErr = sql.Open("postgres", cfg.ConnectionString())
+		if openErr != nil {
+			return
+		}
+
+		instance.SetMaxOpenConns(cfg.MaxOpenConns)
+		instance.SetMaxIdleConns(cfg.MaxIdleConns)
+		instance.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	})
+
+	return instance, openErr
+}
+
+// Ping reports whether the pool opened by Open can still reach the
+// database, for wiring into a health-check endpoint.
+func Ping(ctx context.Context) error {
+	if instance == nil {
+		return errors.New("db: not opened")
+	}
+	return instance.PingContext(ctx)
+}
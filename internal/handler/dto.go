@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// IdentifyRequest is the public request body for POST /identify. Per the
+// Bitespeed spec, phoneNumber arrives as a string, and it's kept as one
+// throughout — parsing it into a number would silently drop a leading zero
+// on a national number.
+type IdentifyRequest struct {
+	Email       *string `json:"email"`
+	PhoneNumber *string `json:"phoneNumber"`
+}
+
+// ValidationError reports a single bad field on an IdentifyRequest, in
+// enough detail for an adapter to build the {"error","field"} body the API
+// contract expects.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// normalizeIdentifyRequest validates req and converts it into the
+// ContactRequest shape consolidateContacts operates on: email lowercased
+// and trimmed, phone number validated but otherwise kept as the original
+// string so leading zeros survive.
+func normalizeIdentifyRequest(req IdentifyRequest) (ContactRequest, *ValidationError) {
+	var contact ContactRequest
+
+	email := ""
+	if req.Email != nil {
+		email = strings.ToLower(strings.TrimSpace(*req.Email))
+	}
+
+	phone := ""
+	if req.PhoneNumber != nil {
+		phone = strings.TrimSpace(*req.PhoneNumber)
+	}
+
+	if email == "" && phone == "" {
+		return contact, &ValidationError{Field: "email", Message: "either email or phoneNumber is required"}
+	}
+
+	if phone != "" {
+		if !phonePattern.MatchString(phone) {
+			return contact, &ValidationError{Field: "phoneNumber", Message: "phoneNumber must be a valid phone number"}
+		}
+
+		contact.PhoneNumber = sql.NullString{String: phone, Valid: true}
+	}
+
+	if email != "" {
+		contact.Email = sql.NullString{String: email, Valid: true}
+	}
+
+	return contact, nil
+}
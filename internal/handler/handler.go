@@ -0,0 +1,422 @@
+// Package handler implements the Bitespeed contact-reconciliation logic
+// independently of whatever transport invokes it. Adapters (Lambda, HTTP)
+// live alongside it in this package: they decode their native request
+// format into an IdentifyRequest, validate and normalize it into a
+// ContactRequest, and call Handler.IdentifyContact.
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ContactRequest is the validated, normalized shape consolidateContacts
+// operates on. It's built by normalizeIdentifyRequest from the public
+// IdentifyRequest DTO, never decoded from JSON directly, so a caller can't
+// smuggle an id or linking fields into it.
+type ContactRequest struct {
+	ID          int
+	Email       sql.NullString
+	PhoneNumber sql.NullString
+}
+
+type ConsolidatedContact struct {
+	PrimaryContactID    int      `json:"primaryContactId"`
+	Emails              []string `json:"emails"`
+	PhoneNumbers        []string `json:"phoneNumbers"`
+	SecondaryContactIDs []int    `json:"secondaryContactIds"`
+}
+
+// contactRow mirrors a row of the contact table, including the linking
+// metadata the reconciliation needs but that has no place in ContactRequest
+// or ConsolidatedContact. PhoneNumber is stored and compared as text, not
+// parsed into a number, so a leading zero in a national number is never
+// silently dropped.
+type contactRow struct {
+	ID             int
+	PhoneNumber    sql.NullString
+	Email          sql.NullString
+	LinkPrecedence string
+	LinkedID       sql.NullInt64
+	CreatedAt      time.Time
+}
+
+// Handler holds the dependencies IdentifyContact needs, shared across
+// whichever adapter (Lambda, HTTP) is driving it.
+type Handler struct {
+	db *sql.DB
+}
+
+func NewHandler(db *sql.DB) *Handler {
+	return &Handler{db: db}
+}
+
+// IdentifyContact is the transport-agnostic entry point that every adapter
+// ultimately calls: it takes the incoming email/phone, resolves the
+// connected component of existing contacts, and reconciles it.
+func (h *Handler) IdentifyContact(ctx context.Context, req ContactRequest) (ConsolidatedContact, error) {
+	return h.consolidateContacts(req)
+}
+
+// consolidateContacts resolves the full connected component reachable from
+// the incoming email/phone (transitively, via shared contact rows) and
+// reconciles it against the Bitespeed linking rules. The whole operation
+// runs in a single transaction with the touched rows locked FOR UPDATE so
+// two concurrent invocations can't both decide to create a fresh primary
+// for the same identity.
+func (h *Handler) consolidateContacts(req ContactRequest) (ConsolidatedContact, error) {
+	var consolidated ConsolidatedContact
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return consolidated, err
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE only locks rows that already exist, so it can't stop two
+	// concurrent requests for the same brand-new identity from both seeing
+	// zero rows and both inserting a primary. Take an advisory lock keyed
+	// on the identity first so concurrent first-time requests serialize:
+	// the second one blocks here until the first commits its insert, and
+	// then finds the row fetchConnectedContacts just created.
+	if err := acquireIdentityLocks(tx, req.Email.String, req.PhoneNumber.String); err != nil {
+		return consolidated, err
+	}
+
+	rows, err := fetchConnectedContacts(tx, req.Email.String, req.PhoneNumber.String)
+	if err != nil {
+		return consolidated, err
+	}
+
+	if len(rows) == 0 {
+		if err := createPrimaryContact(tx, &req); err != nil {
+			return consolidated, err
+		}
+		if err := tx.Commit(); err != nil {
+			return consolidated, err
+		}
+
+		consolidated.PrimaryContactID = req.ID
+		if req.Email.Valid {
+			consolidated.Emails = append(consolidated.Emails, req.Email.String)
+		}
+		if req.PhoneNumber.Valid {
+			consolidated.PhoneNumbers = append(consolidated.PhoneNumbers, req.PhoneNumber.String)
+		}
+		return consolidated, nil
+	}
+
+	primary := oldestPrimary(rows)
+
+	for _, row := range rows {
+		if row.ID == primary.ID {
+			continue
+		}
+		if row.LinkPrecedence == "primary" || (row.LinkedID.Valid && int(row.LinkedID.Int64) != primary.ID) {
+			if err := repointToPrimary(tx, row.ID, primary.ID); err != nil {
+				return consolidated, err
+			}
+		}
+	}
+
+	consolidated.PrimaryContactID = primary.ID
+	consolidated.Emails = extractUniqueEmails(rows, primary)
+	consolidated.PhoneNumbers = extractUniquePhoneNumbers(rows, primary)
+	consolidated.SecondaryContactIDs = extractAllSecondaryContactIDs(rows, primary.ID)
+
+	if shouldCreateSecondaryContact(req, consolidated) {
+		if err := createSecondaryContact(tx, &req, primary.ID); err != nil {
+			return consolidated, err
+		}
+		consolidated.SecondaryContactIDs = append(consolidated.SecondaryContactIDs, req.ID)
+
+		if req.Email.Valid && !containsString(consolidated.Emails, req.Email.String) {
+			consolidated.Emails = append(consolidated.Emails, req.Email.String)
+		}
+		if req.PhoneNumber.Valid && !containsString(consolidated.PhoneNumbers, req.PhoneNumber.String) {
+			consolidated.PhoneNumbers = append(consolidated.PhoneNumbers, req.PhoneNumber.String)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return consolidated, err
+	}
+
+	return consolidated, nil
+}
+
+// acquireIdentityLocks takes a Postgres advisory lock, scoped to the
+// transaction, for each of the incoming email/phone. Locks are acquired in
+// a fixed order (sorted key) so two requests racing on two shared
+// identities can't deadlock against each other.
+func acquireIdentityLocks(tx *sql.Tx, email, phone string) error {
+	for _, key := range lockKeys(email, phone) {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lockKeys(email, phone string) []int64 {
+	var keys []int64
+	if email != "" {
+		keys = append(keys, lockKey("email:"+email))
+	}
+	if phone != "" {
+		keys = append(keys, lockKey(fmt.Sprintf("phone:%s", phone)))
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
+
+func lockKey(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
+// fetchConnectedContacts performs a union-find style expansion: it starts
+// from the incoming email/phone and repeatedly re-queries for any row that
+// shares an email, phone, or linkedId with what's been found so far, until
+// a pass turns up nothing new. This is what catches transitive links (A<->B
+// via email, B<->C via phone) that a single direct lookup would miss.
+func fetchConnectedContacts(tx *sql.Tx, email, phone string) ([]contactRow, error) {
+	emails := map[string]bool{}
+	phones := map[string]bool{}
+	ids := map[int]bool{}
+
+	if email != "" {
+		emails[email] = true
+	}
+	if phone != "" {
+		phones[phone] = true
+	}
+
+	byID := map[int]contactRow{}
+
+	for {
+		batch, err := queryConnected(tx, setKeysString(emails), setKeysString(phones), setKeysInt(ids))
+		if err != nil {
+			return nil, err
+		}
+
+		grew := false
+		for _, row := range batch {
+			if _, ok := byID[row.ID]; !ok {
+				byID[row.ID] = row
+				grew = true
+			}
+			if row.Email.Valid && !emails[row.Email.String] {
+				emails[row.Email.String] = true
+				grew = true
+			}
+			if row.PhoneNumber.Valid && !phones[row.PhoneNumber.String] {
+				phones[row.PhoneNumber.String] = true
+				grew = true
+			}
+			if row.LinkedID.Valid && !ids[int(row.LinkedID.Int64)] {
+				ids[int(row.LinkedID.Int64)] = true
+				grew = true
+			}
+		}
+
+		if !grew {
+			break
+		}
+	}
+
+	rows := make([]contactRow, 0, len(byID))
+	for _, row := range byID {
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func queryConnected(tx *sql.Tx, emails, phones []string, ids []int) ([]contactRow, error) {
+	if len(emails) == 0 && len(phones) == 0 && len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, phoneNumber, email, linkPrecedence, linkedId, createdAt
+		FROM contact
+		WHERE deletedAt IS NULL
+		  AND (email = ANY($1) OR phoneNumber = ANY($2) OR id = ANY($3))
+		FOR UPDATE`
+
+	rows, err := tx.Query(query, pq.Array(emails), pq.Array(phones), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []contactRow
+	for rows.Next() {
+		var row contactRow
+		if err := rows.Scan(&row.ID, &row.PhoneNumber, &row.Email, &row.LinkPrecedence, &row.LinkedID, &row.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// oldestPrimary picks the row that should remain `primary` for the
+// component: the primary-precedence row with the smallest createdAt,
+// breaking ties on id. Every component is guaranteed to contain at least
+// one primary row, since every secondary's linkedId chain terminates in one.
+func oldestPrimary(rows []contactRow) contactRow {
+	var candidates []contactRow
+	for _, row := range rows {
+		if row.LinkPrecedence == "primary" {
+			candidates = append(candidates, row)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].CreatedAt.Equal(candidates[j].CreatedAt) {
+			return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	return candidates[0]
+}
+
+// repointToPrimary demotes row (if it's currently a primary) and/or
+// re-parents it onto the winning primary. It's a single UPDATE either way:
+// a demoted primary just picks up linkPrecedence='secondary' alongside the
+// new linkedId.
+func repointToPrimary(tx *sql.Tx, id, primaryID int) error {
+	query := `UPDATE contact SET linkPrecedence = 'secondary', linkedId = $1, updatedAt = now() WHERE id = $2`
+	_, err := tx.Exec(query, primaryID, id)
+	return err
+}
+
+func createPrimaryContact(tx *sql.Tx, req *ContactRequest) error {
+	query := `INSERT INTO contact (phoneNumber, email, linkPrecedence) VALUES ($1, $2, 'primary') RETURNING id`
+	err := tx.QueryRow(query, req.PhoneNumber, req.Email).Scan(&req.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func shouldCreateSecondaryContact(req ContactRequest, consolidated ConsolidatedContact) bool {
+	hasEmail := req.Email.Valid && req.Email.String != ""
+	hasPhone := req.PhoneNumber.Valid && req.PhoneNumber.String != ""
+
+	emailKnown := !hasEmail
+	for _, email := range consolidated.Emails {
+		if email == req.Email.String {
+			emailKnown = true
+		}
+	}
+
+	phoneKnown := !hasPhone
+	for _, phoneNumber := range consolidated.PhoneNumbers {
+		if phoneNumber == req.PhoneNumber.String {
+			phoneKnown = true
+		}
+	}
+
+	return !(emailKnown && phoneKnown)
+}
+
+func createSecondaryContact(tx *sql.Tx, req *ContactRequest, primaryContactID int) error {
+	query := `INSERT INTO contact (phoneNumber, email, linkPrecedence, linkedId) VALUES ($1, $2, 'secondary', $3) RETURNING id`
+	err := tx.QueryRow(query, req.PhoneNumber, req.Email, primaryContactID).Scan(&req.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// extractUniqueEmails returns the component's emails with the primary
+// contact's email first, matching the Bitespeed response contract.
+func extractUniqueEmails(rows []contactRow, primary contactRow) []string {
+	emailSet := make(map[string]bool)
+	var emails []string
+
+	addEmail := func(row contactRow) {
+		if row.Email.Valid && !emailSet[row.Email.String] {
+			emailSet[row.Email.String] = true
+			emails = append(emails, row.Email.String)
+		}
+	}
+
+	addEmail(primary)
+	for _, row := range rows {
+		addEmail(row)
+	}
+
+	return emails
+}
+
+// extractUniquePhoneNumbers mirrors extractUniqueEmails for phone numbers.
+func extractUniquePhoneNumbers(rows []contactRow, primary contactRow) []string {
+	phoneSet := make(map[string]bool)
+	var phoneNumbers []string
+
+	addPhone := func(row contactRow) {
+		if row.PhoneNumber.Valid && !phoneSet[row.PhoneNumber.String] {
+			phoneSet[row.PhoneNumber.String] = true
+			phoneNumbers = append(phoneNumbers, row.PhoneNumber.String)
+		}
+	}
+
+	addPhone(primary)
+	for _, row := range rows {
+		addPhone(row)
+	}
+
+	return phoneNumbers
+}
+
+func extractAllSecondaryContactIDs(rows []contactRow, primaryID int) []int {
+	var secondaryContactIDs []int
+
+	for _, row := range rows {
+		if row.ID != primaryID {
+			secondaryContactIDs = append(secondaryContactIDs, row.ID)
+		}
+	}
+
+	return secondaryContactIDs
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func setKeysString(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func setKeysInt(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterRoutes mounts the HTTP adapter for Handler on an echo router.
+func (h *Handler) RegisterRoutes(e *echo.Echo) {
+	e.POST("/identify", h.handleIdentify)
+}
+
+func (h *Handler) handleIdentify(c echo.Context) error {
+	var req IdentifyRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	contact, verr := normalizeIdentifyRequest(req)
+	if verr != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": verr.Message, "field": verr.Field})
+	}
+
+	consolidated, err := h.IdentifyContact(c.Request().Context(), contact)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"contact": consolidated})
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"os"
+	"testing"
+)
+
+// TestIdentifyContact_Integration exercises consolidateContacts end to end
+// against a real Postgres instance, covering the reconciliation branches a
+// mocked DB can't meaningfully stand in for. It's gated behind
+// TEST_INTEGRATION=1 (see `make test-integration`) since it needs Docker.
+func TestIdentifyContact_Integration(t *testing.T) {
+	skipUnlessIntegration(t)
+
+	h := newTestHandler(t)
+
+	scenarios := []struct {
+		name               string
+		seed               []IdentifyRequest
+		request            IdentifyRequest
+		wantEmails         []string
+		wantPhones         []string
+		wantSecondaryCount int
+	}{
+		{
+			name:       "brand new contact",
+			request:    identifyRequest("new@example.com", "1111111"),
+			wantEmails: []string{"new@example.com"},
+			wantPhones: []string{"1111111"},
+		},
+		{
+			name:       "brand new contact with email only",
+			request:    identifyRequest("emailonly@example.com", ""),
+			wantEmails: []string{"emailonly@example.com"},
+			wantPhones: []string{},
+		},
+		{
+			name:       "brand new contact with phone only",
+			request:    identifyRequest("", "6666666"),
+			wantEmails: []string{},
+			wantPhones: []string{"6666666"},
+		},
+		{
+			name:       "exact duplicate does not create a secondary",
+			seed:       []IdentifyRequest{identifyRequest("dup@example.com", "2222222")},
+			request:    identifyRequest("dup@example.com", "2222222"),
+			wantEmails: []string{"dup@example.com"},
+			wantPhones: []string{"2222222"},
+		},
+		{
+			name:               "matching email only creates a secondary",
+			seed:               []IdentifyRequest{identifyRequest("shared@example.com", "3333333")},
+			request:            identifyRequest("shared@example.com", "4444444"),
+			wantEmails:         []string{"shared@example.com"},
+			wantPhones:         []string{"3333333", "4444444"},
+			wantSecondaryCount: 1,
+		},
+		{
+			name:               "matching phone only creates a secondary",
+			seed:               []IdentifyRequest{identifyRequest("owner@example.com", "5555555")},
+			request:            identifyRequest("other@example.com", "5555555"),
+			wantEmails:         []string{"owner@example.com", "other@example.com"},
+			wantPhones:         []string{"5555555"},
+			wantSecondaryCount: 1,
+		},
+		{
+			name:               "phone-only request against an existing contact creates a secondary with no email",
+			seed:               []IdentifyRequest{identifyRequest("anchor@example.com", "7777777")},
+			request:            identifyRequest("", "7777777"),
+			wantEmails:         []string{"anchor@example.com"},
+			wantPhones:         []string{"7777777"},
+			wantSecondaryCount: 1,
+		},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			resetContactTable(t, h)
+
+			for _, seed := range sc.seed {
+				if _, err := identify(t, h, seed); err != nil {
+					t.Fatalf("seeding %+v: %v", seed, err)
+				}
+			}
+
+			got, err := identify(t, h, sc.request)
+			if err != nil {
+				t.Fatalf("IdentifyContact: %v", err)
+			}
+
+			if !sameStrings(got.Emails, sc.wantEmails) {
+				t.Errorf("emails = %v, want %v", got.Emails, sc.wantEmails)
+			}
+			if !sameStrings(got.PhoneNumbers, sc.wantPhones) {
+				t.Errorf("phoneNumbers = %v, want %v", got.PhoneNumbers, sc.wantPhones)
+			}
+			if len(got.SecondaryContactIDs) != sc.wantSecondaryCount {
+				t.Errorf("secondaryContactIds = %v, want %d entries", got.SecondaryContactIDs, sc.wantSecondaryCount)
+			}
+		})
+	}
+}
+
+// TestIdentifyContact_PrimaryPrimaryMerge_Integration covers the case two
+// independently-created primaries turn out to share an identity: the
+// request that bridges them must demote the younger primary and report the
+// older one as primaryContactId.
+func TestIdentifyContact_PrimaryPrimaryMerge_Integration(t *testing.T) {
+	skipUnlessIntegration(t)
+
+	h := newTestHandler(t)
+	resetContactTable(t, h)
+
+	first, err := identify(t, h, identifyRequest("george@example.com", "919191"))
+	if err != nil {
+		t.Fatalf("seeding first primary: %v", err)
+	}
+
+	second, err := identify(t, h, identifyRequest("biff@example.com", "717171"))
+	if err != nil {
+		t.Fatalf("seeding second primary: %v", err)
+	}
+
+	bridged, err := identify(t, h, identifyRequest("george@example.com", "717171"))
+	if err != nil {
+		t.Fatalf("bridging request: %v", err)
+	}
+
+	olderPrimary := first.PrimaryContactID
+	if second.PrimaryContactID < first.PrimaryContactID {
+		olderPrimary = second.PrimaryContactID
+	}
+
+	if bridged.PrimaryContactID != olderPrimary {
+		t.Errorf("primaryContactId = %d, want the older of the two primaries (%d)", bridged.PrimaryContactID, olderPrimary)
+	}
+	if !sameStrings(bridged.Emails, []string{"george@example.com", "biff@example.com"}) {
+		t.Errorf("emails = %v, want both primaries' emails merged", bridged.Emails)
+	}
+}
+
+// TestIdentifyContact_TransitiveChain_Integration covers a chain of length
+// 3 (A<->B via phone, B<->C via a second phone) to make sure the union-find
+// expansion in fetchConnectedContacts doesn't stop after one hop.
+func TestIdentifyContact_TransitiveChain_Integration(t *testing.T) {
+	skipUnlessIntegration(t)
+
+	h := newTestHandler(t)
+	resetContactTable(t, h)
+
+	if _, err := identify(t, h, identifyRequest("a@example.com", "1000001")); err != nil {
+		t.Fatalf("seeding chain link 1: %v", err)
+	}
+	if _, err := identify(t, h, identifyRequest("b@example.com", "1000001")); err != nil {
+		t.Fatalf("seeding chain link 2: %v", err)
+	}
+	if _, err := identify(t, h, identifyRequest("b@example.com", "1000002")); err != nil {
+		t.Fatalf("seeding chain link 3: %v", err)
+	}
+
+	got, err := identify(t, h, identifyRequest("", "1000002"))
+	if err != nil {
+		t.Fatalf("querying chain tail: %v", err)
+	}
+
+	if !sameStrings(got.Emails, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("emails = %v, want the full transitive chain", got.Emails)
+	}
+	if len(got.SecondaryContactIDs) != 2 {
+		t.Errorf("secondaryContactIds = %v, want 2 entries for a 3-contact chain", got.SecondaryContactIDs)
+	}
+}
+
+func skipUnlessIntegration(t *testing.T) {
+	t.Helper()
+	if os.Getenv("TEST_INTEGRATION") != "1" {
+		t.Skip("set TEST_INTEGRATION=1 to run integration tests against a dockerized Postgres")
+	}
+}
+
+func sameStrings(got, want []string) bool {
+	return sameElements(got, want)
+}
+
+func sameElements[T comparable](got, want []T) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[T]bool, len(want))
+	for _, w := range want {
+		seen[w] = true
+	}
+	for _, g := range got {
+		if !seen[g] {
+			return false
+		}
+	}
+	return true
+}
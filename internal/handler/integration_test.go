@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+
+	"github.com/monkwithamac23/bitespeed_backend_/internal/config"
+	"github.com/monkwithamac23/bitespeed_backend_/internal/db"
+)
+
+// testDB is the Postgres pool shared by every integration test in this
+// package, started once in TestMain against a throwaway dockertest
+// container.
+var testDB *sql.DB
+
+// TestMain spins up Postgres via dockertest and runs migrations before any
+// integration test runs, and tears the container down afterward. It's a
+// no-op when TEST_INTEGRATION isn't set, so `go test ./...` stays fast and
+// Docker-free by default.
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_INTEGRATION") != "1" {
+		os.Exit(m.Run())
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		log.Fatalf("integration test: connecting to docker: %v", err)
+	}
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Env: []string{
+			"POSTGRES_USER=bitespeed",
+			"POSTGRES_PASSWORD=bitespeed",
+			"POSTGRES_DB=bitespeed",
+		},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+	})
+	if err != nil {
+		log.Fatalf("integration test: starting postgres container: %v", err)
+	}
+
+	cfg := config.Config{
+		Host:     "localhost",
+		Port:     mustAtoi(resource.GetPort("5432/tcp")),
+		User:     "bitespeed",
+		Password: "bitespeed",
+		DBName:   "bitespeed",
+		SSLMode:  "disable",
+	}
+
+	if err := pool.Retry(func() error {
+		testDB, err = db.Open(cfg)
+		if err != nil {
+			return err
+		}
+		return testDB.Ping()
+	}); err != nil {
+		log.Fatalf("integration test: waiting for postgres: %v", err)
+	}
+
+	if err := db.Migrate(testDB); err != nil {
+		log.Fatalf("integration test: running migrations: %v", err)
+	}
+
+	code := m.Run()
+
+	if err := pool.Purge(resource); err != nil {
+		log.Printf("integration test: purging postgres container: %v", err)
+	}
+
+	os.Exit(code)
+}
+
+func mustAtoi(port string) int {
+	var n int
+	if _, err := fmt.Sscanf(port, "%d", &n); err != nil {
+		log.Fatalf("integration test: parsing container port %q: %v", port, err)
+	}
+	return n
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	return NewHandler(testDB)
+}
+
+func resetContactTable(t *testing.T, h *Handler) {
+	t.Helper()
+	if _, err := h.db.Exec("TRUNCATE TABLE contact RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("resetting contact table: %v", err)
+	}
+}
+
+func identifyRequest(email, phone string) IdentifyRequest {
+	req := IdentifyRequest{}
+	if email != "" {
+		req.Email = &email
+	}
+	if phone != "" {
+		req.PhoneNumber = &phone
+	}
+	return req
+}
+
+func identify(t *testing.T, h *Handler, req IdentifyRequest) (ConsolidatedContact, error) {
+	t.Helper()
+
+	contact, verr := normalizeIdentifyRequest(req)
+	if verr != nil {
+		return ConsolidatedContact{}, verr
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return h.IdentifyContact(ctx, contact)
+}
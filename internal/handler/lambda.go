@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// LambdaHandler adapts Handler to the AWS Lambda API Gateway proxy contract.
+// It's the function passed to lambda.Start by cmd/main.go.
+func (h *Handler) LambdaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req IdentifyRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return jsonErrorResponse(400, err.Error(), "")
+	}
+
+	contact, verr := normalizeIdentifyRequest(req)
+	if verr != nil {
+		return jsonErrorResponse(400, verr.Message, verr.Field)
+	}
+
+	consolidated, err := h.IdentifyContact(ctx, contact)
+	if err != nil {
+		return jsonErrorResponse(500, err.Error(), "")
+	}
+
+	responseBody, err := json.Marshal(map[string]interface{}{"contact": consolidated})
+	if err != nil {
+		return jsonErrorResponse(500, err.Error(), "")
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       string(responseBody),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+func jsonErrorResponse(status int, message, field string) (events.APIGatewayProxyResponse, error) {
+	body := map[string]string{"error": message}
+	if field != "" {
+		body["field"] = field
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: err.Error()}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       string(encoded),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}, nil
+}